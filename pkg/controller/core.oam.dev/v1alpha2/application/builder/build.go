@@ -9,6 +9,8 @@ import (
 	"cuelang.org/go/cue/build"
 	cueparser "cuelang.org/go/cue/parser"
 	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
@@ -18,6 +20,26 @@ import (
 
 type builder struct {
 	app *parser.Appfile
+	// loaders is nil for Build, which renders via CompleteWithContext and
+	// re-parses templates on every call; BuildWithLoaderFactory and
+	// BuildForEnvironments set it and render via Complete instead, which is
+	// the only path that actually goes through a loaderFactory's
+	// process.TemplateCache (and, via NewModuleLoaderFactory, its CUE
+	// module root) rather than evaluating workloads/traits directly.
+	loaders *loaderFactory
+	// envOverlay is nil for Build; BuildForEnvironments sets it to the
+	// Environment's overlay, folded into each component's compCtx so
+	// templates can read context.env.
+	envOverlay map[string]interface{}
+}
+
+// loader returns the loaderFactory this builder renders with, falling back
+// to the uncached default.
+func (b *builder) loader() *loaderFactory {
+	if b.loaders != nil {
+		return b.loaders
+	}
+	return defaultLoaderFactory
 }
 
 const (
@@ -25,14 +47,35 @@ const (
 	OamApplicationLabel = "application.oam.dev"
 )
 
-// Build template to applicationConfig & Component
-func Build(ns string, app *parser.Appfile) (*v1alpha2.ApplicationConfiguration, []*v1alpha2.Component, error) {
-	b := &builder{app}
+// Build template to applicationConfig & Component. The third return value
+// holds auxiliary resources (Services, ConfigMaps, Ingresses, ...) that a
+// template declared via an `outputs: <name>: {...}` block. Call
+// SetOutputOwners once the ApplicationConfiguration has a real UID to wire
+// up garbage collection for them.
+func Build(ns string, app *parser.Appfile) (*v1alpha2.ApplicationConfiguration, []*v1alpha2.Component, []*unstructured.Unstructured, error) {
+	b := &builder{app: app}
 	return b.CompleteWithContext(ns)
 }
 
-// Complete: builder complete rendering
-func (b *builder) Complete(ns string) (*v1alpha2.ApplicationConfiguration, []*v1alpha2.Component, error) {
+// BuildWithLoaderFactory is Build, but renders through factory via Complete
+// instead of CompleteWithContext. Pass a factory created by
+// NewCachedLoaderFactory to reuse parsed template ASTs across calls, or by
+// NewModuleLoaderFactory to resolve templates' CUE imports against a real
+// module — neither has any effect on CompleteWithContext, which never
+// consults a loaderFactory at all.
+func BuildWithLoaderFactory(ns string, app *parser.Appfile, factory *loaderFactory) (*v1alpha2.ApplicationConfiguration, []*v1alpha2.Component, []*unstructured.Unstructured, error) {
+	b := &builder{app: app, loaders: factory}
+	return b.Complete(ns)
+}
+
+// Complete renders ns through the legacy Render-based loader
+// (wl.Eval/trait.Eval), the only path that actually goes through
+// b.loader(): a loaderFactory's cache and module root are both consulted
+// by loader.Complete, which CompleteWithContext's wl.EvalContext never
+// calls. It supports the same named outputs CompleteWithContext does, by
+// pulling the `outputs: <name>: {...}` block a template declared off the
+// rendered workload object.
+func (b *builder) Complete(ns string) (*v1alpha2.ApplicationConfiguration, []*v1alpha2.Component, []*unstructured.Unstructured, error) {
 	appconfig := &v1alpha2.ApplicationConfiguration{}
 	appconfig.SetGroupVersionKind(v1alpha2.ApplicationConfigurationGroupVersionKind)
 	appconfig.Name = b.app.Name()
@@ -45,13 +88,23 @@ func (b *builder) Complete(ns string) (*v1alpha2.ApplicationConfiguration, []*v1
 	appconfig.Labels[OamApplicationLabel] = b.app.Name()
 
 	componets := []*v1alpha2.Component{}
+	var outputs []*unstructured.Unstructured
 	for _, wl := range b.app.Services() {
-		compCtx := map[string]string{"name": wl.Name()}
+		compCtx := map[string]interface{}{"name": wl.Name()}
+		if b.envOverlay != nil {
+			compCtx["env"] = b.envOverlay
+		}
+
+		component, err := wl.Eval(b.loader().newLoader(compCtx))
+		if err != nil {
+			return nil, nil, nil, err
+		}
 
-		component, err := wl.Eval(newLoader(compCtx))
+		compOutputs, err := extractOutputs(component)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
+		outputs = append(outputs, compOutputs...)
 
 		component.Namespace = ns
 		component.Name = wl.Name()
@@ -68,18 +121,61 @@ func (b *builder) Complete(ns string) (*v1alpha2.ApplicationConfiguration, []*v1
 		}
 
 		for _, trait := range wl.Traits() {
-			ctraits, err := trait.Eval(newLoader(compCtx))
+			ctraits, err := trait.Eval(b.loader().newLoader(compCtx))
 			if err != nil {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
 			comp.Traits = append(comp.Traits, ctraits...)
 		}
 		appconfig.Spec.Components = append(appconfig.Spec.Components, comp)
 	}
-	return appconfig, componets, nil
+
+	for _, out := range outputs {
+		out.SetNamespace(ns)
+	}
+
+	return appconfig, componets, outputs, nil
 }
 
-func (b *builder) CompleteWithContext(ns string) (*v1alpha2.ApplicationConfiguration, []*v1alpha2.Component, error) {
+// extractOutputs pulls the named auxiliary resources a template declared
+// via `outputs: <name>: {...}` off component's rendered workload object,
+// deleting the key so it doesn't leak into the workload manifest itself.
+func extractOutputs(component *v1alpha2.Component) ([]*unstructured.Unstructured, error) {
+	raw, ok := component.Spec.Workload.Object.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	outputsRaw, ok := raw["outputs"]
+	if !ok {
+		return nil, nil
+	}
+	delete(raw, "outputs")
+
+	outputsMap, ok := outputsRaw.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	outputs := make([]*unstructured.Unstructured, 0, len(outputsMap))
+	for name, v := range outputsMap {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		u := &unstructured.Unstructured{Object: obj}
+		if u.GetName() == "" {
+			u.SetName(name)
+		}
+		outputs = append(outputs, u)
+	}
+	return outputs, nil
+}
+
+// CompleteWithContext renders ns by evaluating workloads/traits directly
+// via wl.EvalContext/tr.EvalContext, bypassing the legacy Render-based
+// loader entirely. It never consults a loaderFactory — use Complete (via
+// BuildWithLoaderFactory/BuildForEnvironments) when caching or a CUE
+// module root matters.
+func (b *builder) CompleteWithContext(ns string) (*v1alpha2.ApplicationConfiguration, []*v1alpha2.Component, []*unstructured.Unstructured, error) {
 	appconfig := &v1alpha2.ApplicationConfiguration{}
 	appconfig.SetGroupVersionKind(v1alpha2.ApplicationConfigurationGroupVersionKind)
 	appconfig.Name = b.app.Name()
@@ -92,19 +188,20 @@ func (b *builder) CompleteWithContext(ns string) (*v1alpha2.ApplicationConfigura
 	appconfig.Labels[OamApplicationLabel] = b.app.Name()
 
 	componets := []*v1alpha2.Component{}
+	var outputs []*unstructured.Unstructured
 	for _, wl := range b.app.Services() {
 		pCtx := process.NewContext(wl.Name())
 		if err := wl.EvalContext(pCtx); err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		for _, tr := range wl.Traits() {
 			if err := tr.EvalContext(pCtx); err != nil {
-				return nil, nil, err
+				return nil, nil, nil, err
 			}
 		}
-		comp, acComp, err := generateOAM(pCtx)
+		comp, acComp, compOutputs, err := generateOAM(pCtx)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		comp.Name = wl.Name()
 		acComp.ComponentName = comp.Name
@@ -118,17 +215,57 @@ func (b *builder) CompleteWithContext(ns string) (*v1alpha2.ApplicationConfigura
 
 		componets = append(componets, comp)
 		appconfig.Spec.Components = append(appconfig.Spec.Components, *acComp)
+		outputs = append(outputs, compOutputs...)
 	}
 
-	return appconfig, componets, nil
+	for _, out := range outputs {
+		out.SetNamespace(ns)
+	}
+
+	return appconfig, componets, outputs, nil
+}
+
+// SetOutputOwners sets each output's owner reference to appconfig. Call it
+// after appconfig has been created on (or read back from) the API server:
+// CompleteWithContext only renders in memory and never assigns a UID, and
+// Kubernetes' garbage collector can't correlate an OwnerReference with an
+// empty UID to anything.
+func SetOutputOwners(appconfig *v1alpha2.ApplicationConfiguration, outputs []*unstructured.Unstructured) error {
+	if appconfig.GetUID() == "" {
+		return errors.Errorf("builder: ApplicationConfiguration %s/%s has no UID yet; create it before setting output owner references", appconfig.Namespace, appconfig.Name)
+	}
+	owner := metav1.NewControllerRef(appconfig, v1alpha2.ApplicationConfigurationGroupVersionKind)
+	for _, out := range outputs {
+		out.SetOwnerReferences([]metav1.OwnerReference{*owner})
+	}
+	return nil
 }
 
-func generateOAM(pCtx process.Context) (*v1alpha2.Component, *v1alpha2.ApplicationConfigurationComponent, error) {
+// generateOAM turns a rendered Context into the Component, the
+// ApplicationConfiguration's trait refs for it, and the named auxiliary
+// outputs (Services, ConfigMaps, Ingresses, ...) the template declared via
+// `outputs: <name>: {...}`, so controllers can apply them alongside the
+// primary workload without a new TraitDefinition per side-object.
+func generateOAM(pCtx process.Context) (*v1alpha2.Component, *v1alpha2.ApplicationConfigurationComponent, []*unstructured.Unstructured, error) {
 	base, assists := pCtx.Output()
 	componetWorkload, err := base.Object(nil)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
+
+	// Pull any `outputs: <name>: {...}` the template declared off the base
+	// workload and record them on pCtx, so the collection loop below has
+	// something to collect.
+	declaredOutputs, err := process.ExtractOutputs(base)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	pCtx.AppendOutputs(declaredOutputs)
+	// Drop the outputs block from the workload object itself: it's been
+	// pulled into pCtx's outputs above, and leaving it in place would have
+	// every Component carry a stray, non-schema `outputs` field alongside
+	// its real workload (e.g. a Deployment) spec.
+	delete(componetWorkload, "outputs")
 	component := &v1alpha2.Component{}
 	component.Spec.Workload.Object = componetWorkload
 
@@ -137,7 +274,7 @@ func generateOAM(pCtx process.Context) (*v1alpha2.Component, *v1alpha2.Applicati
 	for _, assist := range assists {
 		traitRef, err := assist.Object(nil)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
 		acComponent.Traits = append(acComponent.Traits, v1alpha2.ComponentTrait{
 			Trait: runtime.RawExtension{
@@ -145,25 +282,30 @@ func generateOAM(pCtx process.Context) (*v1alpha2.Component, *v1alpha2.Applicati
 			},
 		})
 	}
-	return component, acComponent, nil
+
+	var outputs []*unstructured.Unstructured
+	for name, out := range pCtx.Outputs() {
+		obj, err := out.Object(nil)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		u := &unstructured.Unstructured{Object: obj}
+		if u.GetName() == "" {
+			u.SetName(name)
+		}
+		outputs = append(outputs, u)
+	}
+
+	return component, acComponent, outputs, nil
 }
 
 type loader struct {
 	files map[string]*ast.File
 	err   error
-}
-
-func newLoader(ctx interface{}) parser.Render {
-	l := &loader{
-		files: map[string]*ast.File{},
-	}
-	const key = "context"
-	f, err := cueparser.ParseFile(key, marshal(key, ctx))
-	if err != nil {
-		l.err = errors.Errorf("loader parse %s error", key)
-	}
-	l.files[key] = f
-	return l
+	cache *process.TemplateCache
+	// moduleRoot, if set, has Complete resolve CUE imports against the real
+	// module rooted there instead of an anonymous in-memory instance.
+	moduleRoot string
 }
 
 // WithTemplate: loader add template
@@ -171,6 +313,20 @@ func (l *loader) WithTemplate(raw string) parser.Render {
 	if l.err != nil {
 		return l
 	}
+	if l.cache != nil {
+		if f, ok := l.cache.Get(raw); ok {
+			l.files["-"] = f
+			return l
+		}
+		f, err := cueparser.ParseFile("-", raw)
+		if err != nil {
+			l.err = errors.Errorf("loader parse template error")
+			return l
+		}
+		l.cache.Add(raw, f)
+		l.files["-"] = f
+		return l
+	}
 	f, err := cueparser.ParseFile("-", raw)
 	if err != nil {
 		l.err = errors.Errorf("loader parse template error")
@@ -212,7 +368,16 @@ func (l *loader) Complete() (*cue.Instance, error) {
 	if l.err != nil {
 		return nil, l.err
 	}
-	bi := build.NewContext().NewInstance("", nil)
+	var bi *build.Instance
+	if l.moduleRoot != "" {
+		var err error
+		bi, err = moduleInstance(l.moduleRoot)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		bi = build.NewContext().NewInstance("", nil)
+	}
 	for fname, f := range l.files {
 		if err := bi.AddSyntax(f); err != nil {
 			return nil, errors.WithMessagef(err, "loader AddSyntax %s", fname)
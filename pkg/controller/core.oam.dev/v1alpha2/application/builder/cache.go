@@ -0,0 +1,54 @@
+package builder
+
+import (
+	"cuelang.org/go/cue/ast"
+	cueparser "cuelang.org/go/cue/parser"
+	"github.com/pkg/errors"
+
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+)
+
+// defaultLoaderFactory is what builder.loader falls back to when a builder
+// was given no loaderFactory (e.g. BuildForEnvironments with a nil factory);
+// it has no cache and no module root, so every loader parses its template
+// afresh into an anonymous in-memory instance.
+var defaultLoaderFactory = &loaderFactory{}
+
+// loaderFactory produces loaders that share a process.TemplateCache, so
+// repeated Build calls against the same WorkloadDefinition/TraitDefinition
+// revisions don't re-parse their template CUE on every component and trait
+// evaluation. Construct one with NewCachedLoaderFactory. The cache type lives
+// in pkg/dsl/process, not here; only Complete's loaders consult it, and
+// CompleteWithContext does not use a loaderFactory at all.
+type loaderFactory struct {
+	cache *process.TemplateCache
+	// moduleRoot is set by NewModuleLoaderFactory so loaders resolve CUE
+	// imports (including generated CRD packages under cue.mod/gen) against
+	// a real module instead of an anonymous in-memory instance.
+	moduleRoot string
+}
+
+// NewCachedLoaderFactory returns a loaderFactory backed by an in-process LRU
+// cache holding up to size parsed template ASTs. Pass the factory to
+// BuildWithLoaderFactory or BuildForEnvironments so repeated renders of apps
+// with many components reuse the parsed template ASTs instead of re-parsing
+// them per component and trait. Only parsing is cached: each loader still
+// builds and compiles its own CUE instance in Complete.
+func NewCachedLoaderFactory(size int) *loaderFactory {
+	return &loaderFactory{cache: process.NewTemplateCache(size)}
+}
+
+func (f *loaderFactory) newLoader(ctx interface{}) *loader {
+	l := &loader{
+		files:      map[string]*ast.File{},
+		cache:      f.cache,
+		moduleRoot: f.moduleRoot,
+	}
+	const key = "context"
+	fi, err := cueparser.ParseFile(key, marshal(key, ctx))
+	if err != nil {
+		l.err = errors.Errorf("loader parse %s error", key)
+	}
+	l.files[key] = fi
+	return l
+}
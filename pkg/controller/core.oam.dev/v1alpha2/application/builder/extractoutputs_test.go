@@ -0,0 +1,38 @@
+package builder
+
+import (
+	"testing"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+)
+
+// TestExtractOutputsStripsOutputsFromComponent covers the Eval-based
+// Complete path's outputs support: extractOutputs reads the `outputs:
+// <name>: {...}` block off a rendered Component's workload object and
+// strips it so it doesn't leak into the workload manifest itself.
+func TestExtractOutputsStripsOutputsFromComponent(t *testing.T) {
+	comp := &v1alpha2.Component{}
+	comp.Spec.Workload.Object = map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"outputs": map[string]interface{}{
+			"cm": map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+			},
+		},
+	}
+
+	outputs, err := extractOutputs(comp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outputs) != 1 || outputs[0].GetName() != "cm" {
+		t.Fatalf("got outputs %+v, want a single %q output", outputs, "cm")
+	}
+
+	workload := comp.Spec.Workload.Object.(map[string]interface{})
+	if _, ok := workload["outputs"]; ok {
+		t.Fatal("extractOutputs left the outputs block in the workload object")
+	}
+}
@@ -0,0 +1,65 @@
+package builder
+
+import (
+	"testing"
+
+	"github.com/oam-dev/kubevela/pkg/dsl/process"
+)
+
+// fakeWorkload is a process.Workload backed by a fixed decoded object, used
+// to drive generateOAM without a real CUE evaluation.
+type fakeWorkload struct {
+	obj map[string]interface{}
+}
+
+func (f *fakeWorkload) Object(paths []string) (map[string]interface{}, error) {
+	if len(paths) == 0 {
+		return f.obj, nil
+	}
+	cur := f.obj
+	for _, p := range paths {
+		next, ok := cur[p]
+		if !ok {
+			return nil, nil
+		}
+		cur, ok = next.(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+	}
+	return cur, nil
+}
+
+// TestGenerateOAMStripsOutputsFromWorkload guards against the outputs block
+// leaking into the primary workload object alongside being collected as a
+// named auxiliary resource.
+func TestGenerateOAMStripsOutputsFromWorkload(t *testing.T) {
+	pCtx := process.NewContext("web")
+	pCtx.SetBase(&fakeWorkload{obj: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"outputs": map[string]interface{}{
+			"svc": map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Service",
+			},
+		},
+	}})
+
+	comp, _, outputs, err := generateOAM(pCtx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workload, ok := comp.Spec.Workload.Object.(map[string]interface{})
+	if !ok {
+		t.Fatalf("workload object has unexpected type %T", comp.Spec.Workload.Object)
+	}
+	if _, ok := workload["outputs"]; ok {
+		t.Fatal("generateOAM left the outputs block in the workload object")
+	}
+
+	if len(outputs) != 1 || outputs[0].GetName() != "svc" {
+		t.Fatalf("got outputs %+v, want a single %q output", outputs, "svc")
+	}
+}
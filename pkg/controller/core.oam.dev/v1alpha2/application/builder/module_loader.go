@@ -0,0 +1,36 @@
+package builder
+
+import (
+	"cuelang.org/go/cue/build"
+	"cuelang.org/go/cue/load"
+	"github.com/pkg/errors"
+)
+
+// NewModuleLoaderFactory returns a loaderFactory whose loaders resolve CUE
+// `import` statements against a real module rooted at root, instead of the
+// anonymous in-memory instance the default loader builds. root must contain
+// a cue.mod/module.cue, the same module layout pkg/dsl/gen.GenerateFromCRD
+// writes its generated definitions under (cue.mod/gen/<group>/<version>).
+// Pass the result to BuildWithLoaderFactory or BuildForEnvironments so
+// WorkloadDefinition/TraitDefinition templates can `import` those generated
+// packages and get compile-time validation of the objects they emit,
+// instead of hand-writing loose CUE structs. Both of those build via
+// Complete, the loaderFactory-consulting render path — moduleRoot has no
+// effect on CompleteWithContext, which never touches a loaderFactory.
+func NewModuleLoaderFactory(root string) *loaderFactory {
+	return &loaderFactory{moduleRoot: root}
+}
+
+// moduleInstance loads root's module graph (including anything under its
+// cue.mod/gen) so the *build.Instance returned can resolve the same import
+// paths a `cue` CLI invocation rooted there would.
+func moduleInstance(root string) (*build.Instance, error) {
+	insts := load.Instances([]string{"."}, &load.Config{Dir: root})
+	if len(insts) == 0 {
+		return nil, errors.Errorf("loader: no instances found at module root %s", root)
+	}
+	if insts[0].Err != nil {
+		return nil, errors.WithMessagef(insts[0].Err, "loader: load module at %s", root)
+	}
+	return insts[0], nil
+}
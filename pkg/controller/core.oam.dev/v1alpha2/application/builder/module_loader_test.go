@@ -0,0 +1,17 @@
+package builder
+
+import "testing"
+
+// TestModuleLoaderFactoryThreadsModuleRoot guards against NewModuleLoaderFactory
+// becoming a no-op again: its moduleRoot must reach every loader it produces,
+// since that's what loader.Complete branches on to resolve CUE imports
+// against a real module instead of an anonymous in-memory instance.
+func TestModuleLoaderFactoryThreadsModuleRoot(t *testing.T) {
+	const root = "/tmp/does-not-need-to-exist-for-this-check"
+	factory := NewModuleLoaderFactory(root)
+
+	l := factory.newLoader(map[string]interface{}{"name": "web"})
+	if l.moduleRoot != root {
+		t.Fatalf("got loader.moduleRoot %q, want %q", l.moduleRoot, root)
+	}
+}
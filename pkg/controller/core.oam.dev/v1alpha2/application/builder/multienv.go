@@ -0,0 +1,69 @@
+package builder
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/oam-dev/kubevela/apis/core.oam.dev/v1alpha2"
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/application/parser"
+)
+
+const (
+	// OamClusterLabel marks which Environment.Cluster a render targets.
+	OamClusterLabel = "cluster.oam.dev"
+)
+
+// Environment is one rendering target for BuildForEnvironments: a name, the
+// namespace/cluster it renders into, and an overlay that templates read as
+// context.env. The overlay is not merged into parameters, and no patches
+// are applied from it.
+type Environment struct {
+	Name      string
+	Namespace string
+	Cluster   string
+	Overlay   map[string]interface{}
+}
+
+// RenderedApp is one Environment's render output.
+type RenderedApp struct {
+	ApplicationConfiguration *v1alpha2.ApplicationConfiguration
+	Components               []*v1alpha2.Component
+	Outputs                  []*unstructured.Unstructured
+}
+
+// BuildForEnvironments evaluates app once per Environment, folding each
+// Environment's overlay into the rendering context before calling
+// Complete, and returns the per-environment render keyed by
+// Environment.Name. This lets one Appfile produce prod/staging/canary
+// renders in a single pass. Pass a factory from NewCachedLoaderFactory to
+// additionally share parsed template ASTs across every environment's
+// render, or from NewModuleLoaderFactory to resolve templates' CUE
+// imports against a real module; pass nil for the uncached default.
+func BuildForEnvironments(envs []Environment, app *parser.Appfile, factory *loaderFactory) (map[string]*RenderedApp, error) {
+	rendered := make(map[string]*RenderedApp, len(envs))
+	for _, env := range envs {
+		if _, exists := rendered[env.Name]; exists {
+			return nil, errors.Errorf("builder: duplicate environment name %q", env.Name)
+		}
+
+		b := &builder{app: app, loaders: factory, envOverlay: env.Overlay}
+		appconfig, components, outputs, err := b.Complete(env.Namespace)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "builder: render environment %q", env.Name)
+		}
+
+		if env.Cluster != "" {
+			if appconfig.Labels == nil {
+				appconfig.Labels = map[string]string{}
+			}
+			appconfig.Labels[OamClusterLabel] = env.Cluster
+		}
+
+		rendered[env.Name] = &RenderedApp{
+			ApplicationConfiguration: appconfig,
+			Components:               components,
+			Outputs:                  outputs,
+		}
+	}
+	return rendered, nil
+}
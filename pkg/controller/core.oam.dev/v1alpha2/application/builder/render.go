@@ -0,0 +1,80 @@
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/oam-dev/kubevela/pkg/controller/core.oam.dev/v1alpha2/application/parser"
+)
+
+// RenderToYAML renders the ApplicationConfiguration, its Components and any
+// named outputs the templates declared to a multi-document YAML stream,
+// without submitting anything to the API
+// server. It is the building block behind a `vela render`/`kubectl diff`
+// style workflow: inspect exactly what the CUE templates produce, pipe the
+// result through kustomize, or check it into git. Marshalling goes through
+// sigs.k8s.io/yaml so numeric/JSON-tag fidelity is preserved across the
+// runtime.RawExtension traits.
+func RenderToYAML(ns string, app *parser.Appfile) ([]byte, error) {
+	docs, err := renderDocs(ns, app)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for i, doc := range docs {
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		out, err := yaml.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(out)
+	}
+	return buf.Bytes(), nil
+}
+
+// RenderToJSON is the JSON sibling of RenderToYAML: the same rendered
+// objects, as a JSON array in the same order (ApplicationConfiguration,
+// then Components, then named outputs).
+func RenderToJSON(ns string, app *parser.Appfile) ([]byte, error) {
+	docs, err := renderDocs(ns, app)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(docs)
+}
+
+// renderDocs reuses CompleteWithContext to dry-run render an Appfile,
+// without submitting it, and returns the resulting objects in a stable
+// order suitable for both YAML and JSON encoding.
+func renderDocs(ns string, app *parser.Appfile) ([]interface{}, error) {
+	b := &builder{app: app}
+	appconfig, components, outputs, err := b.CompleteWithContext(ns)
+	if err != nil {
+		return nil, err
+	}
+
+	// Outputs are collected from a map, so their order varies between runs;
+	// sort them by kind, then name, to keep the rendered stream stable.
+	sort.SliceStable(outputs, func(i, j int) bool {
+		if outputs[i].GetKind() != outputs[j].GetKind() {
+			return outputs[i].GetKind() < outputs[j].GetKind()
+		}
+		return outputs[i].GetName() < outputs[j].GetName()
+	})
+
+	docs := make([]interface{}, 0, len(components)+len(outputs)+1)
+	docs = append(docs, appconfig)
+	for _, c := range components {
+		docs = append(docs, c)
+	}
+	for _, o := range outputs {
+		docs = append(docs, o)
+	}
+	return docs, nil
+}
@@ -0,0 +1,65 @@
+package gen
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// NewCommand returns the `crd` subcommand that reads a CRD from a local
+// YAML file or a discovery URL and writes the translated `.cue` definitions
+// into a module-relative cue.mod/gen/<group>/<version> directory. Wire it
+// under the vela CLI's `dsl gen` command group.
+func NewCommand() *cobra.Command {
+	var (
+		file string
+		url  string
+		out  string
+	)
+	cmd := &cobra.Command{
+		Use:   "crd",
+		Short: "Generate CUE definitions from a CRD's OpenAPI v3 schema",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			raw, err := loadCRD(file, url)
+			if err != nil {
+				return err
+			}
+			crd := &apiextensionsv1.CustomResourceDefinition{}
+			if err := yaml.Unmarshal(raw, crd); err != nil {
+				return errors.WithMessage(err, "gen: decode CRD")
+			}
+			written, err := GenerateFromCRD(crd, out)
+			if err != nil {
+				return err
+			}
+			for _, path := range written {
+				cmd.Println(path)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&file, "file", "", "path to a CRD YAML file")
+	cmd.Flags().StringVar(&url, "url", "", "discovery URL serving the CRD YAML")
+	cmd.Flags().StringVar(&out, "out", "cue.mod/gen", "module-relative output directory")
+	return cmd
+}
+
+func loadCRD(file, url string) ([]byte, error) {
+	switch {
+	case file != "":
+		return ioutil.ReadFile(file)
+	case url != "":
+		resp, err := http.Get(url) // nolint:gosec // URL is an operator-supplied CLI flag, not untrusted input
+		if err != nil {
+			return nil, errors.WithMessage(err, "gen: fetch CRD")
+		}
+		defer resp.Body.Close()
+		return ioutil.ReadAll(resp.Body)
+	default:
+		return nil, errors.New("gen: one of --file or --url is required")
+	}
+}
@@ -0,0 +1,176 @@
+// Package gen translates a CRD's OpenAPI v3 schema into `.cue` definitions,
+// in the style of `cue get go`: a `#<Kind>: {...}` definition per served
+// version, with required/optional markers and enum constraints. Of the
+// x-kubernetes-* extensions, int-or-string and preserve-unknown-fields are
+// kept as field comments; the others are not carried over.
+// WorkloadDefinition/TraitDefinition
+// authors can then import the generated package and get compile-time
+// validation of the objects they emit, instead of hand-writing loose CUE
+// structs.
+package gen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"cuelang.org/go/cue/format"
+	cueparser "cuelang.org/go/cue/parser"
+	"github.com/pkg/errors"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// GenerateFromCRD writes one `.cue` file per served version of crd under
+// <outDir>/<group>/<version>/<kind>.cue, so it can be imported from a
+// module-relative cue.mod/gen path. It returns the paths written.
+func GenerateFromCRD(crd *apiextensionsv1.CustomResourceDefinition, outDir string) ([]string, error) {
+	kind := crd.Spec.Names.Kind
+	if kind == "" {
+		return nil, errors.New("gen: CRD has no spec.names.kind")
+	}
+
+	var written []string
+	for _, v := range crd.Spec.Versions {
+		if !v.Served || v.Schema == nil || v.Schema.OpenAPIV3Schema == nil {
+			continue
+		}
+		// The package name matches the last element of the import path
+		// <group>/<version>, so templates can import the generated file.
+		src := fmt.Sprintf("package %s\n\n#%s: %s\n", v.Name, kind, schemaToSource(v.Schema.OpenAPIV3Schema, 0))
+		f, err := cueparser.ParseFile(strings.ToLower(kind)+".cue", src)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "gen: parse generated definition for %s/%s", crd.Spec.Group, v.Name)
+		}
+		out, err := format.Node(f)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "gen: format %s/%s", crd.Spec.Group, v.Name)
+		}
+
+		path := filepath.Join(outDir, crd.Spec.Group, v.Name, strings.ToLower(kind)+".cue")
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, errors.WithMessagef(err, "gen: mkdir %s", filepath.Dir(path))
+		}
+		if err := os.WriteFile(path, out, 0o644); err != nil {
+			return nil, errors.WithMessagef(err, "gen: write %s", path)
+		}
+		written = append(written, path)
+	}
+	sort.Strings(written)
+	return written, nil
+}
+
+// schemaToSource translates an OpenAPI v3 schema into CUE source text,
+// mirroring the approach builder.marshal uses elsewhere in this repo:
+// build a source string and let the CUE parser turn it into an ast.File,
+// rather than constructing ast nodes by hand.
+func schemaToSource(schema *apiextensionsv1.JSONSchemaProps, depth int) string {
+	if schema == nil {
+		return "_"
+	}
+	if len(schema.Enum) > 0 {
+		return enumSource(schema.Enum)
+	}
+	switch schema.Type {
+	case "object":
+		return objectSource(schema, depth)
+	case "array":
+		var item *apiextensionsv1.JSONSchemaProps
+		if schema.Items != nil {
+			item = schema.Items.Schema
+		}
+		return "[..." + schemaToSource(item, depth) + "]"
+	case "string":
+		return "string"
+	case "integer":
+		return "int"
+	case "number":
+		return "number"
+	case "boolean":
+		return "bool"
+	default:
+		return "_"
+	}
+}
+
+func objectSource(schema *apiextensionsv1.JSONSchemaProps, depth int) string {
+	required := make(map[string]bool, len(schema.Required))
+	for _, r := range schema.Required {
+		required[r] = true
+	}
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	indent := strings.Repeat("\t", depth+1)
+	var b strings.Builder
+	b.WriteString("{\n")
+	for _, name := range names {
+		prop := schema.Properties[name]
+		for _, line := range fieldDoc(prop) {
+			b.WriteString(indent + "// " + line + "\n")
+		}
+		mark := ":"
+		if !required[name] {
+			mark = "?:"
+		}
+		fmt.Fprintf(&b, "%s%s%s %s\n", indent, cueLabel(name), mark, schemaToSource(&prop, depth+1))
+	}
+	switch {
+	case schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil:
+		fmt.Fprintf(&b, "%s[string]: %s\n", indent, schemaToSource(schema.AdditionalProperties.Schema, depth+1))
+	case schema.AdditionalProperties != nil && schema.AdditionalProperties.Allows:
+		// additionalProperties: true, with no schema of its own: open the
+		// struct so CUE (a closed struct by default) doesn't reject fields
+		// the CRD itself permits.
+		fmt.Fprintf(&b, "%s...\n", indent)
+	}
+	b.WriteString(strings.Repeat("\t", depth) + "}")
+	return b.String()
+}
+
+func enumSource(enum []apiextensionsv1.JSON) string {
+	parts := make([]string, 0, len(enum))
+	for _, e := range enum {
+		parts = append(parts, string(e.Raw))
+	}
+	return strings.Join(parts, " | ")
+}
+
+// cueLabel quotes field names that aren't valid bare CUE identifiers, e.g.
+// ones containing dashes or dots.
+func cueLabel(name string) string {
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && r >= '0' && r <= '9':
+		default:
+			return fmt.Sprintf("%q", name)
+		}
+	}
+	return name
+}
+
+// fieldDoc renders a schema's description together with its
+// x-kubernetes-int-or-string and x-kubernetes-preserve-unknown-fields
+// markers (no other x-kubernetes-* extension), as the individual lines of a `//` comment block, so
+// a generated definition keeps the provenance a hand-written CUE struct
+// would otherwise lose. Descriptions routinely contain embedded newlines;
+// splitting on them and emitting one `//` per line is what keeps those from
+// terminating the comment early and leaving the rest as bare, invalid CUE.
+func fieldDoc(schema apiextensionsv1.JSONSchemaProps) []string {
+	var lines []string
+	if schema.Description != "" {
+		lines = append(lines, strings.Split(schema.Description, "\n")...)
+	}
+	if schema.XIntOrString {
+		lines = append(lines, "+x-kubernetes-int-or-string")
+	}
+	if schema.XPreserveUnknownFields != nil && *schema.XPreserveUnknownFields {
+		lines = append(lines, "+x-kubernetes-preserve-unknown-fields")
+	}
+	return lines
+}
@@ -0,0 +1,92 @@
+package process
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"cuelang.org/go/cue/ast"
+	"cuelang.org/go/cue/ast/astutil"
+)
+
+// TemplateCache is a fixed-size, concurrency-safe LRU cache of parsed
+// template ast.Files, keyed by a fingerprint of the template source so
+// identical templates evaluated for different components share one parse.
+// It backs builder.loaderFactory, so repeated renders of apps with many
+// components reuse the template parse the legacy Render-based loader would
+// otherwise redo per component and trait. It holds parsed ASTs only, not
+// compiled CUE instances.
+type TemplateCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+type templateCacheEntry struct {
+	key  string
+	file *ast.File
+}
+
+// NewTemplateCache returns a TemplateCache holding up to size parsed
+// template ASTs, evicting the least recently used entry once full.
+func NewTemplateCache(size int) *TemplateCache {
+	if size <= 0 {
+		size = 256
+	}
+	return &TemplateCache{
+		size:  size,
+		order: list.New(),
+		items: map[string]*list.Element{},
+	}
+}
+
+// Get returns a deep copy of the cached ast.File for raw's fingerprint,
+// never the cached pointer itself. cue.Build's identifier resolution
+// mutates a File's scope/node info in place, so handing out the same
+// *ast.File to two concurrent callers sharing this cache would race them
+// against each other.
+func (c *TemplateCache) Get(raw string) (*ast.File, bool) {
+	key := Fingerprint(raw)
+	c.mu.Lock()
+	el, ok := c.items[key]
+	if !ok {
+		c.mu.Unlock()
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	f := el.Value.(*templateCacheEntry).file
+	c.mu.Unlock()
+	return astutil.Copy(f).(*ast.File), true
+}
+
+// Add records f as the parsed ast.File for raw's fingerprint.
+func (c *TemplateCache) Add(raw string, f *ast.File) {
+	key := Fingerprint(raw)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value.(*templateCacheEntry).file = f
+		c.order.MoveToFront(el)
+		return
+	}
+	el := c.order.PushFront(&templateCacheEntry{key: key, file: f})
+	c.items[key] = el
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		if oldest != nil {
+			c.order.Remove(oldest)
+			delete(c.items, oldest.Value.(*templateCacheEntry).key)
+		}
+	}
+}
+
+// Fingerprint identifies a template body for cache lookups. The template
+// source is the only thing that determines its parsed ast.File, so hashing
+// it is enough to dedupe across components and traits that share a
+// WorkloadDefinition/TraitDefinition revision.
+func Fingerprint(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
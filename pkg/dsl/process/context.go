@@ -0,0 +1,130 @@
+// Package process holds the per-component rendering state threaded through
+// CUE evaluation of a workload and its traits.
+package process
+
+// Workload is the base object a component template renders into; exactly
+// one per component.
+type Workload interface {
+	Object(paths []string) (map[string]interface{}, error)
+}
+
+// Auxiliary is a side object attached to a Workload, such as a trait, or a
+// named output declared by the template's `outputs` block.
+type Auxiliary interface {
+	Object(paths []string) (map[string]interface{}, error)
+}
+
+// Context carries the per-component rendering state (the base workload, its
+// trait auxiliaries, and any named outputs) through CUE evaluation of a
+// workload and its traits.
+type Context interface {
+	SetBase(workload Workload)
+	AppendAuxiliaries(auxiliaries ...Auxiliary)
+	// Output returns the base workload and the flat list of trait
+	// auxiliaries collected so far.
+	Output() (Workload, []Auxiliary)
+	// AppendOutputs records the named auxiliary resources declared by a
+	// template's `outputs: <name>: {...}` block, e.g. outputs: svc: {...}.
+	AppendOutputs(outputs map[string]Auxiliary)
+	// Outputs returns the outputs recorded via AppendOutputs, keyed by the
+	// name the template declared them under.
+	Outputs() map[string]Auxiliary
+	// PushData exposes an extra top-level field to CUE evaluation,
+	// alongside the built-in context.name, e.g. PushData("env", overlay)
+	// makes context.env available to the template.
+	PushData(key string, data interface{})
+	// GetData returns a value pushed via PushData.
+	GetData(key string) (interface{}, bool)
+}
+
+type templateContext struct {
+	name    string
+	base    Workload
+	assists []Auxiliary
+	outputs map[string]Auxiliary
+	data    map[string]interface{}
+}
+
+// NewContext creates a Context for rendering the named component.
+func NewContext(name string) Context {
+	return &templateContext{name: name, outputs: map[string]Auxiliary{}, data: map[string]interface{}{}}
+}
+
+func (ctx *templateContext) SetBase(workload Workload) {
+	ctx.base = workload
+}
+
+func (ctx *templateContext) AppendAuxiliaries(auxiliaries ...Auxiliary) {
+	ctx.assists = append(ctx.assists, auxiliaries...)
+}
+
+func (ctx *templateContext) Output() (Workload, []Auxiliary) {
+	return ctx.base, ctx.assists
+}
+
+func (ctx *templateContext) AppendOutputs(outputs map[string]Auxiliary) {
+	for name, out := range outputs {
+		ctx.outputs[name] = out
+	}
+}
+
+func (ctx *templateContext) Outputs() map[string]Auxiliary {
+	return ctx.outputs
+}
+
+func (ctx *templateContext) PushData(key string, data interface{}) {
+	ctx.data[key] = data
+}
+
+func (ctx *templateContext) GetData(key string) (interface{}, bool) {
+	v, ok := ctx.data[key]
+	return v, ok
+}
+
+// outputsPath is the sub-path a template's named auxiliary resources are
+// declared under, e.g. `outputs: svc: {...}, cm: {...}`.
+var outputsPath = []string{"outputs"}
+
+// rawAuxiliary is an Auxiliary backed by an already-decoded object, used for
+// outputs ExtractOutputs has already pulled out of the base workload.
+type rawAuxiliary struct {
+	obj map[string]interface{}
+}
+
+func (r *rawAuxiliary) Object(paths []string) (map[string]interface{}, error) {
+	if len(paths) == 0 {
+		return r.obj, nil
+	}
+	cur := r.obj
+	for _, p := range paths {
+		next, ok := cur[p]
+		if !ok {
+			return nil, nil
+		}
+		cur, ok = next.(map[string]interface{})
+		if !ok {
+			return nil, nil
+		}
+	}
+	return cur, nil
+}
+
+// ExtractOutputs reads the named auxiliary resources a template declared
+// via `outputs: <name>: {...}`, by looking up the base workload's
+// "outputs" sub-path. It returns an empty map, not an error, when the
+// template declared no outputs block at all.
+func ExtractOutputs(base Workload) (map[string]Auxiliary, error) {
+	raw, err := base.Object(outputsPath)
+	if err != nil {
+		return nil, err
+	}
+	outputs := make(map[string]Auxiliary, len(raw))
+	for name, v := range raw {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		outputs[name] = &rawAuxiliary{obj: obj}
+	}
+	return outputs, nil
+}